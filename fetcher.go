@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Fetcher 是下载后端的统一抽象，不同协议（HTTP/HTTPS、日后的 FTP、磁力链接等）
+// 各自实现这个接口，上层只依赖 Resolve/Create/Start/Pause/Resume/Close
+type Fetcher interface {
+	// Resolve 探测资源信息（文件名、大小、是否支持断点续传等），不落盘
+	Resolve(req *Request) (*Resource, error)
+	// Create 根据 Resolve 得到的资源和选项准备好下载任务（分片布局、续传记录等）
+	Create(res *Resource, opts *Options) error
+	// Start 开始/继续下载，阻塞直到完成或出错
+	Start() error
+	// Pause 暂停所有正在进行的下载
+	Pause() error
+	// Resume 从暂停中恢复
+	Resume() error
+	// Close 释放底层资源（连接、文件句柄等）
+	Close() error
+}
+
+// builders 按协议名注册的 Fetcher 构造函数，新增协议只需在 init 中注册一个 builder
+var builders = map[string]func() Fetcher{}
+
+// registerFetcher 注册一种协议的 Fetcher 构造函数
+func registerFetcher(scheme string, build func() Fetcher) {
+	builders[scheme] = build
+}
+
+// NewFetcher 根据 URL 的 scheme 在 builders 中查找对应的实现
+func NewFetcher(rawURL string) (Fetcher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url[%s]: %w", rawURL, err)
+	}
+
+	build, ok := builders[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported protocol[%s]", u.Scheme)
+	}
+	return build(), nil
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	registerFetcher("http", newHTTPFetcher)
+	registerFetcher("https", newHTTPFetcher)
+}
+
+const (
+	kUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.82 Safari/537.36"
+
+	// flushEvery 每写入多少字节就更新一次续传记录
+	flushEvery = 1 << 20 // 1 MiB
+)
+
+// HTTPFetcher 是 Fetcher 针对 HTTP/HTTPS 协议的实现，原 HttpDownloader 的
+// 全部下载逻辑都搬到了这里
+type HTTPFetcher struct {
+	client *http.Client
+
+	headers   map[string]string
+	basicAuth *BasicAuth
+
+	res         *Resource
+	numRoutines int
+	opts        *Options
+
+	journal *Journal
+	resumed bool // true 表示磁盘上的续传记录与当前资源匹配，数据文件里已有的字节要保留
+
+	progressCh chan Progress
+
+	globalLimiter *rate.Limiter
+	perConnLimit  int64 // 字节/秒，0 表示不限速；每个 worker 用它各自构造一个限速器
+
+	mu        sync.Mutex
+	paused    bool
+	pauseCond *sync.Cond
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// newHTTPFetcher 构造一个空的 HTTPFetcher，真正的客户端在 Resolve/Create 中按选项装配
+func newHTTPFetcher() Fetcher {
+	h := &HTTPFetcher{
+		client:        &http.Client{},
+		progressCh:    make(chan Progress, 1),
+		globalLimiter: newRateLimiter(0),
+	}
+	h.pauseCond = sync.NewCond(&h.mu)
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	return h
+}
+
+// Resolve 通过 1 字节的 Range 探测请求识别资源信息：相比 HEAD，这能可靠地
+// 探测出服务器是否支持 Range（部分服务器在 HEAD 响应里谎报 Accept-Ranges）
+func (h *HTTPFetcher) Resolve(req *Request) (*Resource, error) {
+	if req.Options != nil {
+		if err := h.configureClient(req.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := http.NewRequest("GET", req.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create resolve request failed: %w", err)
+	}
+	r.Header.Set("Range", "bytes=0-0")
+	r.Header.Set("User-Agent", kUserAgent)
+	h.applyHeaders(r)
+
+	resp, err := h.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("resolve request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	res := &Resource{
+		URL:          req.URL,
+		FinalURL:     resp.Request.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		res.AcceptRanges = true
+		res.ContentLength = parseContentRangeTotal(resp.Header.Get("Content-Range"), resp.ContentLength)
+	case http.StatusOK:
+		res.AcceptRanges = false
+		res.ContentLength = resp.ContentLength
+	default:
+		return nil, fmt.Errorf("unexpected status code %d while resolving %s", resp.StatusCode, req.URL)
+	}
+
+	res.Filename = filenameFromResponse(resp, res.FinalURL)
+
+	return res, nil
+}
+
+// parseContentRangeTotal 从形如 "bytes 0-0/12345" 的 Content-Range 头中解析出资源总大小，
+// 解析失败时退化为本次响应携带的 Content-Length
+func parseContentRangeTotal(contentRange string, fallback int64) int64 {
+	idx := strings.LastIndex(contentRange, "/")
+	if idx == -1 || idx == len(contentRange)-1 {
+		return fallback
+	}
+	total, err := strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return total
+}
+
+// filenameFromResponse 优先从 Content-Disposition 中取文件名，取不到则回退到 URL 路径
+func filenameFromResponse(resp *http.Response, finalURL string) string {
+	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			if name := params["filename"]; name != "" {
+				return name
+			}
+		}
+	}
+	return path.Base(finalURL)
+}
+
+// applyHeaders 把用户自定义的请求头和 Basic Auth 写到请求上
+func (h *HTTPFetcher) applyHeaders(r *http.Request) {
+	for k, v := range h.headers {
+		r.Header.Set(k, v)
+	}
+	if h.basicAuth != nil {
+		r.SetBasicAuth(h.basicAuth.Username, h.basicAuth.Password)
+	}
+}
+
+// Create 根据 Resolve 得到的资源和选项装配好客户端（cookie jar、TLS、Header、鉴权），
+// 并加载或新建续传记录
+func (h *HTTPFetcher) Create(res *Resource, opts *Options) error {
+	h.res = res
+	h.opts = opts
+	h.numRoutines = opts.NumRoutines
+	if h.numRoutines <= 0 {
+		h.numRoutines = 1
+	}
+	h.globalLimiter = newRateLimiter(opts.LimitBytesPerSec)
+	h.perConnLimit = opts.PerConnLimitBytesPerSec
+
+	if err := h.configureClient(opts); err != nil {
+		return err
+	}
+
+	return h.loadOrCreateJournal()
+}
+
+// configureClient 用 Options 装配 HTTP 客户端：Header、Basic Auth 总是跟随最新的
+// Options 刷新；Transport/Jar 只在尚未装配时才创建，这样 Resolve 阶段为探测请求
+// 提前装配好的连接池和 cookie（例如探测时服务器种下的登录态）不会被 Create 重新覆盖掉
+func (h *HTTPFetcher) configureClient(opts *Options) error {
+	h.headers = opts.Headers
+	h.basicAuth = opts.BasicAuth
+
+	if h.client.Transport == nil {
+		transport := &http.Transport{}
+		if opts.TLSConfig != nil {
+			transport.TLSClientConfig = opts.TLSConfig
+		}
+		h.client.Transport = transport
+	}
+
+	if h.client.Jar == nil {
+		jar := opts.CookieJar
+		if jar == nil {
+			var err error
+			jar, err = cookiejar.New(nil)
+			if err != nil {
+				return fmt.Errorf("create cookie jar failed: %w", err)
+			}
+		}
+		h.client.Jar = jar
+	}
+
+	return nil
+}
+
+// loadOrCreateJournal 检测磁盘上是否存在续传记录：匹配则复用其分片布局，
+// 否则丢弃过期记录并按当前 Split 结果新建一份
+func (h *HTTPFetcher) loadOrCreateJournal() error {
+	journalPath := h.res.Filename + journalSuffix
+
+	existing, err := loadJournal(journalPath)
+	if err != nil {
+		log.Printf("读取续传记录失败，忽略并重新下载: %s", err.Error())
+		existing = nil
+	}
+
+	if existing != nil && existing.matchesResource(h.res) {
+		h.journal = existing
+		h.resumed = true
+		return nil
+	}
+
+	if existing != nil {
+		// 记录与当前资源不匹配（文件已变化），续传记录作废，磁盘上的旧数据也不可信
+		_ = os.Remove(journalPath)
+	}
+
+	h.journal = newJournal(journalPath, h.res)
+	for _, ranges := range h.splitIntoChunks() {
+		h.journal.Chunks = append(h.journal.Chunks, ChunkState{Start: ranges[0], End: ranges[1]})
+	}
+	return nil
+}
+
+// splitIntoChunks 把文件切成很多 [minChunkSize, maxChunkSize] 区间内的定长小块，
+// 而不是按 numRoutines 均分成固定的大块：小块可以被 scheduler 自由地在 worker
+// 间分配和窃取，不会出现某个 worker 卡在一个超大块上拖慢整体进度
+func (h *HTTPFetcher) splitIntoChunks() [][2]int64 {
+	chunkSize := maxChunkSize
+	if perWorker := h.res.ContentLength / int64(h.numRoutines); perWorker > 0 && perWorker < chunkSize {
+		chunkSize = perWorker
+	}
+	if chunkSize < minChunkSize {
+		chunkSize = minChunkSize
+	}
+
+	var chunks [][2]int64
+	for start := int64(0); start < h.res.ContentLength; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= h.res.ContentLength {
+			end = h.res.ContentLength - 1
+		}
+		chunks = append(chunks, [2]int64{start, end})
+	}
+	return chunks
+}
+
+//Start 开始/继续下载，阻塞直到完成或出错
+func (h *HTTPFetcher) Start() error {
+	filename := h.res.Filename
+
+	// 只有续传记录与当前资源匹配时才保留磁盘上已有的内容；否则数据文件要么是全新的，
+	// 要么跟当前资源对不上（大小/ETag 变了），必须截断，防止旧数据的尾部残留导致文件损坏
+	flags := os.O_CREATE | os.O_WRONLY
+	if !h.resumed {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(filename, flags, 0660)
+	if err != nil {
+		return fmt.Errorf("failed to create file, err: %w", err)
+	}
+	f.Close()
+
+	if !h.res.AcceptRanges {
+		fmt.Println("This file does not support multi-coroutine download, now download with common way...")
+		req, err := http.NewRequest("GET", h.res.FinalURL, nil)
+		if err != nil {
+			return fmt.Errorf("create the download request failed, err: %w", err)
+		}
+		req.Header.Set("User-Agent", kUserAgent)
+		h.applyHeaders(req)
+
+		resp, err := h.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to request the url[%s], err: %w", h.res.FinalURL, err)
+		}
+		defer resp.Body.Close()
+		save2file(filename, 0, resp)
+		return h.finish()
+	}
+
+	fmt.Println("Downloading with a dynamic work-stealing scheduler...")
+	if err := h.journal.save(); err != nil {
+		return fmt.Errorf("failed to persist the resume journal, err: %w", err)
+	}
+
+	sched := newScheduler(h)
+
+	progressDone := make(chan struct{})
+	go h.reportProgress(sched, progressDone)
+
+	err = sched.run(h.numRoutines)
+	close(progressDone)
+	if err != nil {
+		return err
+	}
+
+	return h.finish()
+}
+
+// finish 对下载完成的文件做完整性校验，校验通过才删除续传记录；
+// 校验失败时删除已下载的文件并把错误返回给调用方，由 CLI 决定以非零状态码退出
+func (h *HTTPFetcher) finish() error {
+	specs, err := h.buildChecksumSpecs(h.opts)
+	if err != nil {
+		return err
+	}
+
+	if err := h.verifyIntegrity(specs); err != nil {
+		if rmErr := os.Remove(h.res.Filename); rmErr != nil {
+			log.Printf("删除校验失败的文件也出错了: %s", rmErr.Error())
+		}
+		return err
+	}
+
+	// 全部分片下载完成并通过校验才能删除续传记录
+	return h.journal.remove()
+}
+
+// Pause 暂停所有正在进行的分片下载，工作协程在下一次读取前会阻塞
+func (h *HTTPFetcher) Pause() error {
+	h.mu.Lock()
+	h.paused = true
+	h.mu.Unlock()
+	return nil
+}
+
+// Resume 唤醒所有因 Pause 而阻塞的工作协程
+func (h *HTTPFetcher) Resume() error {
+	h.mu.Lock()
+	h.paused = false
+	h.pauseCond.Broadcast()
+	h.mu.Unlock()
+	return nil
+}
+
+// Close 取消所有仍在进行的请求并释放资源
+func (h *HTTPFetcher) Close() error {
+	h.cancel()
+	return nil
+}
+
+// waitIfPaused 在工作协程的读取循环中调用，Pause 期间会阻塞在这里
+func (h *HTTPFetcher) waitIfPaused() {
+	h.mu.Lock()
+	for h.paused {
+		h.pauseCond.Wait()
+	}
+	h.mu.Unlock()
+}
+
+// newKeepAliveClient 为一个 worker 创建专属的、开启长连接复用的 http.Client，
+// 与 Resolve 阶段用的探测客户端分开，避免共享连接池导致 worker 之间互相等待
+func (h *HTTPFetcher) newKeepAliveClient() *http.Client {
+	transport := h.client.Transport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = false
+	transport.MaxIdleConnsPerHost = h.numRoutines + 1
+
+	return &http.Client{Transport: transport, Jar: h.client.Jar}
+}
+
+// currentPerConnLimit 线程安全地读取当前的单连接限速值，供 worker 创建自己的限速器
+func (h *HTTPFetcher) currentPerConnLimit() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.perConnLimit
+}
+
+// splitJournalChunk 把续传记录里 index 对应的分片缩短到 mid，并为剩下的尾部
+// 追加一条新的 ChunkState；被偷取的 worker 据此继续处理剩下更小的 Range
+func (h *HTTPFetcher) splitJournalChunk(index int, mid int64) int {
+	return h.journal.split(index, mid)
+}
+
+// downloadTask 按照 inFlight 记录的任务下载，期间持续检查该任务的 End 是否
+// 被其他 worker 缩短（work stealing）；每写入 flushEvery 字节就落盘并更新进度
+func (h *HTTPFetcher) downloadTask(client *http.Client, inFlight *inFlightTask, stats *WorkerStats, perConnLimiter *rate.Limiter) error {
+	inFlight.mu.Lock()
+	t := inFlight.t
+	start, end := inFlight.cursor, t.end
+	inFlight.mu.Unlock()
+
+	req, err := http.NewRequest("GET", h.res.FinalURL, nil)
+	if err != nil {
+		return fmt.Errorf("create the download request failed, err: %w", err)
+	}
+	req = req.WithContext(h.ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", start, end))
+	req.Header.Set("User-Agent", kUserAgent)
+	h.applyHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute download request, err: %w", err)
+	}
+	defer resp.Body.Close()
+	body := &countingReader{r: resp.Body, counter: &stats.bytesTotal}
+
+	f, err := os.OpenFile(h.res.Filename, os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	journalIndex := t.journalIndex
+	written := int64(0)      // 本次任务累计写入的字节数，用于续传记录的增量计算
+	unflushed := int64(0)    // 距上次 fsync 以来写入的字节数，达到 flushEvery 就清零重新计数
+	buf := make([]byte, 32*1024)
+	for {
+		h.waitIfPaused()
+
+		inFlight.mu.Lock()
+		currentEnd := inFlight.t.end
+		inFlight.mu.Unlock()
+		remaining := currentEnd + 1 - (start + written)
+		if remaining <= 0 {
+			// 这段 Range 的尾部已经被其他 worker 偷走，剩下的交给它
+			break
+		}
+
+		readBuf := buf
+		if remaining < int64(len(buf)) {
+			// 把本次读取长度钳制在被偷走之前剩下的字节数以内，
+			// 避免一次 32KB 的 Read 把已经划给 stealer 的尾部也读写进来
+			readBuf = buf[:remaining]
+		}
+
+		n, readErr := body.Read(readBuf)
+		if n > 0 {
+			if err := throttle(h.ctx, h.globalLimiter, perConnLimiter, n); err != nil {
+				return err
+			}
+			if _, err := f.WriteAt(buf[:n], start+written); err != nil {
+				return err
+			}
+			written += int64(n)
+			unflushed += int64(n)
+
+			inFlight.mu.Lock()
+			inFlight.cursor = start + written
+			inFlight.mu.Unlock()
+
+			if unflushed >= flushEvery {
+				if err := f.Sync(); err != nil {
+					return err
+				}
+				if err := h.journal.markChunk(journalIndex, t.doneAtStart+written); err != nil {
+					return err
+				}
+				unflushed = 0
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return h.journal.markChunk(journalIndex, t.doneAtStart+written)
+}
+
+//save2file 保存Body内容到指定文件区间
+func save2file(filename string, offset int64, resp *http.Response) {
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0660)
+	if err != nil {
+		log.Fatalf("Open file failed, err: %s", err.Error())
+	}
+	// 从文件起点开始进行偏移
+	if _, err := f.Seek(offset, 0); err != nil {
+		log.Fatalf("Seek on a file failed, err: %s", err.Error())
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatalf("Read content from response body failed, err: %s", err.Error())
+	}
+
+	if _, err := f.Write(content); err != nil {
+		log.Fatalf("Write content to file failed, err: %s", err.Error())
+	}
+}
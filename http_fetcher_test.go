@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseContentRangeTotal(t *testing.T) {
+	cases := []struct {
+		contentRange string
+		fallback     int64
+		want         int64
+	}{
+		{"bytes 0-0/12345", 1, 12345},
+		{"bytes 100-200/999", 1, 999},
+		{"", 42, 42},
+		{"bytes 0-0/", 42, 42},
+		{"bytes 0-0/not-a-number", 42, 42},
+	}
+
+	for _, c := range cases {
+		if got := parseContentRangeTotal(c.contentRange, c.fallback); got != c.want {
+			t.Errorf("parseContentRangeTotal(%q, %d) = %d, want %d", c.contentRange, c.fallback, got, c.want)
+		}
+	}
+}
+
+func TestFilenameFromResponse(t *testing.T) {
+	withDisposition := &http.Response{Header: http.Header{"Content-Disposition": []string{`attachment; filename="report.pdf"`}}}
+	if got := filenameFromResponse(withDisposition, "http://example.com/download?id=1"); got != "report.pdf" {
+		t.Errorf("expected filename from Content-Disposition, got %q", got)
+	}
+
+	withoutDisposition := &http.Response{Header: http.Header{}}
+	if got := filenameFromResponse(withoutDisposition, "http://example.com/path/to/file.zip"); got != "file.zip" {
+		t.Errorf("expected filename from URL path, got %q", got)
+	}
+
+	malformedDisposition := &http.Response{Header: http.Header{"Content-Disposition": []string{"???"}}}
+	if got := filenameFromResponse(malformedDisposition, "http://example.com/fallback.bin"); got != "fallback.bin" {
+		t.Errorf("expected fallback to URL path when Content-Disposition is malformed, got %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// checksumSpec 是一份待校验的摘要：算法名 + 期望的十六进制摘要值
+type checksumSpec struct {
+	algo     string
+	expected string
+}
+
+// md5HexPattern 匹配形如 32 位十六进制的强 ETag，用来和 S3 那种
+// multipart 上传产生的 "\"<hex>-<N>\"" 弱标识区分开
+var md5HexPattern = regexp.MustCompile(`^[a-fA-F0-9]{32}$`)
+
+// newHasher 按算法名构造对应的 hash.Hash，目前支持 sha256/sha1/md5
+func newHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+}
+
+// etagAsMD5 如果服务器返回的是强 ETag 且形如 32 位十六进制 MD5，就把它当作一份
+// 免费的校验依据；弱 ETag（W/ 前缀）和 S3 的 "<hex>-<parts>" 格式都不满足
+func etagAsMD5(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if strings.HasPrefix(etag, "W/") {
+		return ""
+	}
+	if !md5HexPattern.MatchString(etag) {
+		return ""
+	}
+	return strings.ToLower(etag)
+}
+
+// buildChecksumSpecs 汇总用户通过 Options 显式指定的摘要、校验清单 URL 里的条目，
+// 以及服务器 ETag 自动推断出的 MD5
+func (h *HTTPFetcher) buildChecksumSpecs(opts *Options) ([]checksumSpec, error) {
+	var specs []checksumSpec
+	for algo, expected := range opts.Checksums {
+		specs = append(specs, checksumSpec{algo: algo, expected: strings.ToLower(expected)})
+	}
+
+	if opts.ChecksumManifestURL != "" {
+		manifest, err := h.fetchManifestChecksum(opts.ChecksumManifestURL, h.res.Filename)
+		if err != nil {
+			return nil, err
+		}
+		if manifest != nil {
+			specs = append(specs, *manifest)
+		}
+	}
+
+	if md5sum := etagAsMD5(h.res.ETag); md5sum != "" {
+		specs = append(specs, checksumSpec{algo: "md5", expected: md5sum})
+	}
+
+	return specs, nil
+}
+
+// fetchManifestChecksum 下载形如 `sha256sum` 输出的校验清单（"<hex>  <filename>" 每行一条），
+// 找到与当前下载文件同名的那一行并按十六进制长度猜出算法
+func (h *HTTPFetcher) fetchManifestChecksum(manifestURL, filename string) (*checksumSpec, error) {
+	resp, err := h.client.Get(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch checksum manifest[%s]: %w", manifestURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d while fetching checksum manifest", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		digest, name := fields[0], strings.TrimPrefix(fields[1], "*")
+		if path.Base(name) != filename {
+			continue
+		}
+
+		algo := algoFromDigestLength(digest)
+		if algo == "" {
+			continue
+		}
+		return &checksumSpec{algo: algo, expected: strings.ToLower(digest)}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// algoFromDigestLength 根据十六进制摘要的长度猜测算法
+func algoFromDigestLength(digest string) string {
+	switch len(digest) {
+	case 64:
+		return "sha256"
+	case 40:
+		return "sha1"
+	case 32:
+		return "md5"
+	default:
+		return ""
+	}
+}
+
+// verifyIntegrity 对下载完成的文件逐一校验 specs 中的摘要；已经在续传记录里
+// 标记过 Verified 的 (算法, 期望摘要) 组合会被跳过，这样多个校验值时某一个失败也不用全部重算。
+// 按组合而不是单纯按算法去重，是因为同一种算法可能出现两次期望值不同的 spec
+// （例如用户显式传入的 --md5 和从 ETag 推导出的 md5），不能让其中一个冒充另一个校验过
+func (h *HTTPFetcher) verifyIntegrity(specs []checksumSpec) error {
+	for _, spec := range specs {
+		if h.journal.isVerified(spec.algo, spec.expected) {
+			continue
+		}
+
+		digest, err := hashFile(h.res.Filename, spec.algo)
+		if err != nil {
+			return err
+		}
+
+		if digest != spec.expected {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", spec.algo, spec.expected, digest)
+		}
+
+		fmt.Printf("%s checksum verified: %s\n", spec.algo, digest)
+		if err := h.journal.markVerified(spec.algo, spec.expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashFile 重新读取整个文件并计算指定算法的摘要
+func hashFile(filename, algo string) (string, error) {
+	hasher, err := newHasher(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestEtagAsMD5(t *testing.T) {
+	cases := []struct {
+		etag string
+		want string
+	}{
+		{`"d41d8cd98f00b204e9800998ecf8427e"`, "d41d8cd98f00b204e9800998ecf8427e"},
+		{`"D41D8CD98F00B204E9800998ECF8427E"`, "d41d8cd98f00b204e9800998ecf8427e"},
+		{`W/"d41d8cd98f00b204e9800998ecf8427e"`, ""},              // weak ETag
+		{`"d41d8cd98f00b204e9800998ecf8427e-5"`, ""},              // S3 multipart form
+		{`"not-a-valid-hex-digest"`, ""},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if got := etagAsMD5(c.etag); got != c.want {
+			t.Errorf("etagAsMD5(%q) = %q, want %q", c.etag, got, c.want)
+		}
+	}
+}
+
+func TestAlgoFromDigestLength(t *testing.T) {
+	if got := algoFromDigestLength("d41d8cd98f00b204e9800998ecf8427e"); got != "md5" {
+		t.Errorf("expected md5 for a 32-char digest, got %q", got)
+	}
+	if got := algoFromDigestLength("a9993e364706816aba3e25717850c26c9cd0d891"); got != "sha1" {
+		t.Errorf("expected sha1 for a 40-char digest, got %q", got)
+	}
+	if got := algoFromDigestLength("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015adf"); got != "" {
+		t.Errorf("expected no match for a 67-char digest, got %q", got)
+	}
+}
+
+func TestBuildChecksumSpecsCombinesUserAndETagDerived(t *testing.T) {
+	h := &HTTPFetcher{res: &Resource{Filename: "a.bin", ETag: `"d41d8cd98f00b204e9800998ecf8427e"`}}
+	opts := &Options{Checksums: map[string]string{"sha256": "ABCDEF"}}
+
+	specs, err := h.buildChecksumSpecs(opts)
+	if err != nil {
+		t.Fatalf("buildChecksumSpecs failed: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected the explicit sha256 spec plus the ETag-derived md5 spec, got %d: %+v", len(specs), specs)
+	}
+
+	var sawSHA256, sawMD5 bool
+	for _, s := range specs {
+		switch s.algo {
+		case "sha256":
+			sawSHA256 = true
+			if s.expected != "abcdef" {
+				t.Errorf("expected user checksum to be lowercased, got %q", s.expected)
+			}
+		case "md5":
+			sawMD5 = true
+			if s.expected != "d41d8cd98f00b204e9800998ecf8427e" {
+				t.Errorf("unexpected ETag-derived md5: %q", s.expected)
+			}
+		}
+	}
+	if !sawSHA256 || !sawMD5 {
+		t.Fatalf("expected both sha256 and md5 specs, got %+v", specs)
+	}
+}
+
+func TestBuildChecksumSpecsNoETagNoChecksums(t *testing.T) {
+	h := &HTTPFetcher{res: &Resource{Filename: "a.bin"}}
+	specs, err := h.buildChecksumSpecs(&Options{})
+	if err != nil {
+		t.Fatalf("buildChecksumSpecs failed: %v", err)
+	}
+	if len(specs) != 0 {
+		t.Fatalf("expected no specs when nothing is configured, got %+v", specs)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+)
+
+// journalSuffix 续传记录文件的后缀名
+const journalSuffix = ".hdpart"
+
+// ChunkState 记录单个分片的下载进度
+type ChunkState struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"` // 已写入磁盘的字节数，相对 Start 的偏移
+}
+
+// Journal 断点续传记录，随下载文件一起落盘在 <filename>.hdpart 中
+type Journal struct {
+	mu sync.Mutex
+
+	path          string
+	Url           string          `json:"url"`
+	ContentLength int64           `json:"contentLength"`
+	ETag          string          `json:"etag"`
+	LastModified  string          `json:"lastModified"`
+	Chunks        []ChunkState    `json:"chunks"`
+	Verified      map[string]bool `json:"verified,omitempty"` // 已经校验通过的 "算法:期望摘要"，避免重启后重复校验
+}
+
+// verifiedKey 把算法和期望摘要拼成 Verified 的 map key，
+// 这样同一种算法的两个不同期望值（例如显式传入的 --md5 和 ETag 推导出的 md5）不会互相冲掉对方的校验状态
+func verifiedKey(algo, expected string) string {
+	return algo + ":" + expected
+}
+
+// newJournal 根据已解析的资源信息创建一份新的续传记录
+func newJournal(path string, res *Resource) *Journal {
+	return &Journal{
+		path:          path,
+		Url:           res.URL,
+		ContentLength: res.ContentLength,
+		ETag:          res.ETag,
+		LastModified:  res.LastModified,
+	}
+}
+
+// loadJournal 从磁盘读取续传记录，文件不存在时返回 nil, nil
+func loadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	j := &Journal{path: path}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// matchesResource 校验记录是否与当前资源匹配，只有 URL、大小和强校验器都一致时才能复用
+func (j *Journal) matchesResource(res *Resource) bool {
+	if j.Url != res.URL || j.ContentLength != res.ContentLength {
+		return false
+	}
+	if res.ETag != "" {
+		return j.ETag == res.ETag
+	}
+	if res.LastModified != "" {
+		return j.LastModified == res.LastModified
+	}
+	// 服务器既没有 ETag 也没有 Last-Modified，无法安全校验，放弃复用
+	return false
+}
+
+// split 把 index 对应的分片缩短到 mid，并为被偷走的尾部追加一条新的 ChunkState，
+// 返回新分片在 Chunks 里的下标
+func (j *Journal) split(index int, mid int64) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	old := j.Chunks[index]
+	j.Chunks[index].End = mid
+	j.Chunks = append(j.Chunks, ChunkState{Start: mid + 1, End: old.End})
+
+	if err := j.flushLocked(); err != nil {
+		log.Printf("持久化续传记录失败（work stealing 拆分分片后）: %s", err.Error())
+	}
+	return len(j.Chunks) - 1
+}
+
+// isVerified 报告某个 (算法, 期望摘要) 组合是否已经在之前的运行中校验通过
+func (j *Journal) isVerified(algo, expected string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.Verified[verifiedKey(algo, expected)]
+}
+
+// markVerified 记录某个 (算法, 期望摘要) 组合校验通过，避免进程重启后对同一个分段重复校验
+func (j *Journal) markVerified(algo, expected string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.Verified == nil {
+		j.Verified = make(map[string]bool)
+	}
+	j.Verified[verifiedKey(algo, expected)] = true
+	return j.flushLocked()
+}
+
+// markChunk 更新指定分片已完成的字节数并立即落盘
+func (j *Journal) markChunk(index int, done int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Chunks[index].Done = done
+	return j.flushLocked()
+}
+
+// flushLocked 将记录序列化并 fsync 到磁盘，调用方需持有 j.mu
+func (j *Journal) flushLocked() error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// save 将当前记录写入磁盘（首次创建时调用）
+func (j *Journal) save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flushLocked()
+}
+
+// remove 下载校验完成后删除续传记录
+func (j *Journal) remove() error {
+	err := os.Remove(j.path)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
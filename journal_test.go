@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalMatchesResource(t *testing.T) {
+	j := &Journal{Url: "http://example.com/a.bin", ContentLength: 100, ETag: `"abc"`}
+
+	if !j.matchesResource(&Resource{URL: "http://example.com/a.bin", ContentLength: 100, ETag: `"abc"`}) {
+		t.Fatal("expected match when URL/size/ETag are identical")
+	}
+	if j.matchesResource(&Resource{URL: "http://example.com/a.bin", ContentLength: 100, ETag: `"def"`}) {
+		t.Fatal("expected mismatch when ETag differs")
+	}
+	if j.matchesResource(&Resource{URL: "http://example.com/a.bin", ContentLength: 99, ETag: `"abc"`}) {
+		t.Fatal("expected mismatch when ContentLength differs")
+	}
+
+	jLastModified := &Journal{Url: "http://example.com/a.bin", ContentLength: 100, LastModified: "Mon"}
+	if !jLastModified.matchesResource(&Resource{URL: "http://example.com/a.bin", ContentLength: 100, LastModified: "Mon"}) {
+		t.Fatal("expected match via Last-Modified when no ETag is present")
+	}
+
+	jNoValidator := &Journal{Url: "http://example.com/a.bin", ContentLength: 100}
+	if jNoValidator.matchesResource(&Resource{URL: "http://example.com/a.bin", ContentLength: 100}) {
+		t.Fatal("expected no reuse when neither side has a validator")
+	}
+}
+
+func TestJournalSplit(t *testing.T) {
+	j := &Journal{
+		path:   filepath.Join(t.TempDir(), "test.hdpart"),
+		Chunks: []ChunkState{{Start: 0, End: 999, Done: 100}},
+	}
+
+	newIndex := j.split(0, 499)
+
+	if j.Chunks[0].End != 499 {
+		t.Fatalf("expected original chunk to shrink to End=499, got %d", j.Chunks[0].End)
+	}
+	if newIndex != 1 {
+		t.Fatalf("expected the stolen tail to be appended at index 1, got %d", newIndex)
+	}
+	if j.Chunks[1].Start != 500 || j.Chunks[1].End != 999 {
+		t.Fatalf("unexpected stolen chunk bounds: %+v", j.Chunks[1])
+	}
+}
+
+func TestJournalVerifiedKeyedByAlgoAndExpected(t *testing.T) {
+	j := &Journal{path: filepath.Join(t.TempDir(), "test.hdpart")}
+
+	if j.isVerified("md5", "aaa") {
+		t.Fatal("nothing should be verified yet")
+	}
+
+	if err := j.markVerified("md5", "aaa"); err != nil {
+		t.Fatalf("markVerified failed: %v", err)
+	}
+
+	if !j.isVerified("md5", "aaa") {
+		t.Fatal("expected (md5, aaa) to be verified")
+	}
+	if j.isVerified("md5", "bbb") {
+		t.Fatal("a different expected digest for the same algo must not be considered verified")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseByteRate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"2MiB/s", 2 << 20},
+		{"500KiB/s", 500 << 10},
+		{"1GiB/s", 1 << 30},
+		{"1024B/s", 1024},
+		{"2048", 2048},
+		{"  2MiB/s  ", 2 << 20},
+	}
+
+	for _, c := range cases {
+		got, err := parseByteRate(c.in)
+		if err != nil {
+			t.Errorf("parseByteRate(%q) returned unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseByteRate(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseByteRateInvalid(t *testing.T) {
+	if _, err := parseByteRate("not-a-rate"); err == nil {
+		t.Fatal("expected an error for a non-numeric rate")
+	}
+}
@@ -3,14 +3,8 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"strings"
-	"sync"
 )
 
 /**
@@ -19,167 +13,91 @@ import (
  * @Description
  */
 
-const (
-	kUserAgent     = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4844.82 Safari/537.36"
-	ProgressBarStr = "\rDownload progress: [%s] %.2f%%"
-)
-
-type HttpDownloader struct {
-	url           string
-	filename      string
-	contentLength int
-	acceptRanges  bool // 是否支持断点续传
-	numRoutines   int  // 同时下载线程数
-}
-
-//NewDownloader 下载器构造方法，传入下载链接和线程数
-func NewDownloader(url string, numThreads int) *HttpDownloader {
-	// 得到文件名
-	filename := path.Base(url)
+func main() {
+	// "https://dldir1.qq.com/qqfile/qq/PCQQ9.5.9/QQ9.5.9.28625.exe"
+	var downloadUrl string
+	var numRoutines int
+	var sha256sum, sha1sum, md5sum, checksumManifest string
+	var limit, perConnLimit string
+	flag.StringVar(&downloadUrl, "u", "", "The file download url.")
+	flag.IntVar(&numRoutines, "n", 6, "The num of go routines, default is 6.")
+	flag.StringVar(&sha256sum, "sha256", "", "Expected sha256 checksum of the downloaded file.")
+	flag.StringVar(&sha1sum, "sha1", "", "Expected sha1 checksum of the downloaded file.")
+	flag.StringVar(&md5sum, "md5", "", "Expected md5 checksum of the downloaded file.")
+	flag.StringVar(&checksumManifest, "checksum-manifest", "", "URL of a checksum manifest (sha256sum/sha1sum/md5sum style output).")
+	flag.StringVar(&limit, "limit", "", "Aggregate download speed cap, e.g. 2MiB/s. Empty means unlimited.")
+	flag.StringVar(&perConnLimit, "per-conn-limit", "", "Per-connection download speed cap, e.g. 500KiB/s. Empty means unlimited.")
+	flag.Parse()
 
-	// 请求url获取header
-	resp, err := http.Head(url)
-	if err != nil {
-		log.Fatal("请求失败")
-		return nil
+	if _, err := url.ParseRequestURI(downloadUrl); err != nil {
+		log.Fatal("The file download url is invalid.")
 	}
 
-	httpDownload := &HttpDownloader{}
-	httpDownload.url = url
-	httpDownload.contentLength = int(resp.ContentLength)
-	httpDownload.numRoutines = numThreads
-	httpDownload.filename = filename
-
-	// 是否支持断点续传
-	httpDownload.acceptRanges = len(resp.Header["Accept-Ranges"]) != 0 && resp.Header["Accept-Ranges"][0] == "bytes"
-
-	return httpDownload
-}
-
-//Download 下载文件方法
-func (h *HttpDownloader) Download() {
-	// 创建文件
-	f, err := os.Create(h.filename)
+	fetcher, err := NewFetcher(downloadUrl)
 	if err != nil {
-		log.Fatalf("Failed to create file, err: %s", err.Error())
+		log.Fatalf("Failed to pick a fetcher for the url, err: %s", err.Error())
 	}
-	defer f.Close()
+	defer fetcher.Close()
 
-	// 根据是否支持断点下载进行不同的下载方式
-	if h.acceptRanges == false {
-		fmt.Println("This file does not support multi-coroutine download, now download with common way...")
-		resp, err := http.Get(h.url)
-		if err != nil {
-			log.Fatalf("Failed to request the url[%s], err: %s", h.url, err.Error())
-		}
-		save2file(h.filename, 0, resp)
-	} else {
-		fmt.Println("Downloading with multi goroutine...")
-		var wg sync.WaitGroup
-		success := make(chan bool, h.numRoutines)
-		for _, ranges := range h.Split() {
-			// 分配任务
-			wg.Add(1)
-			go func(start, end int) {
-				defer func() {
-					success <- true
-					wg.Done()
-				}()
-				h.download(start, end)
-			}(ranges[0], ranges[1])
-		}
-
-		// 下载进度
-		go func() {
-			fmt.Printf(ProgressBarStr, strings.Repeat(" ", 100), 0.0)
-			countSuccess := 0
-			for range success {
-				countSuccess++
-				var currProgress = float64(countSuccess) / float64(h.numRoutines) * 100
-				// 进度条
-				str := strings.Repeat("=", int(currProgress)) + strings.Repeat(" ", 100-int(currProgress))
-				fmt.Printf(ProgressBarStr, str, currProgress)
-			}
-			fmt.Printf(ProgressBarStr, strings.Repeat("=", 100), 100.0)
-		}()
-
-		// 等待所有任务完成
-		wg.Wait()
-		close(success)
+	checksums := map[string]string{}
+	if sha256sum != "" {
+		checksums["sha256"] = sha256sum
 	}
-}
-
-//Split 分割下载任务
-func (h *HttpDownloader) Split() [][]int {
-	var ranges [][]int
-	// 每个小任务的大小
-	blockSize := h.contentLength / h.numRoutines
-	for i := 0; i < h.numRoutines; i++ {
-		start := i * blockSize
-		end := (i+1)*blockSize - 1
-		// 最后一个任务要全部下载完
-		if i == h.numRoutines-1 {
-			end = h.contentLength - 1
-		}
-		ranges = append(ranges, []int{start, end})
+	if sha1sum != "" {
+		checksums["sha1"] = sha1sum
+	}
+	if md5sum != "" {
+		checksums["md5"] = md5sum
 	}
-	return ranges
-}
 
-//download 指定文件区间下载
-func (h *HttpDownloader) download(start, end int) {
-	req, err := http.NewRequest("GET", h.url, nil)
+	limitBytesPerSec, err := parseByteRate(limit)
 	if err != nil {
-		log.Fatalf("Create the download request failed, err: %s", err.Error())
+		log.Fatalf("Invalid -limit value, err: %s", err.Error())
 	}
-	req.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", start, end))
-	req.Header.Set("User-Agent", kUserAgent)
-
-	resp, err := http.DefaultClient.Do(req)
+	perConnLimitBytesPerSec, err := parseByteRate(perConnLimit)
 	if err != nil {
-		log.Fatalf("Failed to execute download request, err: %s", err.Error())
+		log.Fatalf("Invalid -per-conn-limit value, err: %s", err.Error())
 	}
-	defer resp.Body.Close()
 
-	save2file(h.filename, int64(start), resp)
-}
+	opts := &Options{
+		NumRoutines:             numRoutines,
+		Checksums:               checksums,
+		ChecksumManifestURL:     checksumManifest,
+		LimitBytesPerSec:        limitBytesPerSec,
+		PerConnLimitBytesPerSec: perConnLimitBytesPerSec,
+	}
 
-//save2file 保存Body内容到指定文件区间
-func save2file(filename string, offset int64, resp *http.Response) {
-	f, err := os.OpenFile(filename, os.O_WRONLY, 0660)
+	// 把 opts 一并带到 Resolve，这样探测请求本身也走鉴权/TLS/自定义 Header，
+	// 否则需要鉴权才肯响应的站点会在探测这一步就失败
+	res, err := fetcher.Resolve(&Request{URL: downloadUrl, Options: opts})
 	if err != nil {
-		log.Fatalf("Open file failed, err: %s", err.Error())
+		log.Fatalf("Failed to resolve the url, err: %s", err.Error())
 	}
-	// 从文件起点开始进行偏移
-	if _, err := f.Seek(offset, 0); err != nil {
-		log.Fatalf("Seek on a file failed, err: %s", err.Error())
+
+	if err := fetcher.Create(res, opts); err != nil {
+		log.Fatalf("Failed to prepare the download task, err: %s", err.Error())
 	}
-	defer f.Close()
 
-	content, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalf("Read content from response body failed, err: %s", err.Error())
+	if reporter, ok := fetcher.(ProgressReporter); ok {
+		go renderProgress(reporter.Progress())
 	}
 
-	if _, err := f.Write(content); err != nil {
-		log.Fatalf("Write content to file failed, err: %s", err.Error())
+	log.Println("Start the download task...")
+	if err := fetcher.Start(); err != nil {
+		log.Fatalf("Download failed, err: %s", err.Error())
 	}
+	fmt.Println()
 }
 
-func main() {
-	// "https://dldir1.qq.com/qqfile/qq/PCQQ9.5.9/QQ9.5.9.28625.exe"
-	var downloadUrl string
-	var numRoutines int
-	flag.StringVar(&downloadUrl, "u", "", "The file download url.")
-	flag.IntVar(&numRoutines, "n", 6, "The num of go routines, default is 6.")
-	flag.Parse()
-
-	if _, err := url.ParseRequestURI(downloadUrl); err != nil {
-		log.Fatal("The file download url is invalid.")
+// renderProgress 是 Progress 事件的一种消费方式：CLI 把它渲染成一行进度条，
+// 库的其它使用者可以订阅同一个 Progress 通道去驱动自己的 TUI 或状态接口
+func renderProgress(progressCh <-chan Progress) {
+	for p := range progressCh {
+		percent := 0.0
+		if p.Total > 0 {
+			percent = float64(p.Downloaded) / float64(p.Total) * 100
+		}
+		fmt.Printf("\rDownload progress: %.2f%%  %.2f MiB/s  ETA %s        ",
+			percent, p.Speed/(1<<20), p.ETA.Round(1e9))
 	}
-
-	downloader := NewDownloader(downloadUrl, numRoutines)
-
-	fmt.Println("Start the download task...")
-	downloader.Download()
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// BasicAuth 承载 HTTP Basic 认证的用户名密码
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Options 是创建 Fetcher 时的可配置项，所有实现共用同一套选项
+type Options struct {
+	NumRoutines int // 同时下载线程数
+
+	Headers   map[string]string // 额外请求头，会覆盖默认值
+	BasicAuth *BasicAuth
+	CookieJar http.CookieJar
+	TLSConfig *tls.Config // 自定义 TLS 配置，例如跳过证书校验、指定根证书等
+
+	Checksums           map[string]string // 算法名（sha256/sha1/md5）到期望摘要的映射
+	ChecksumManifestURL string            // 校验清单 URL，形如 `sha256sum` 命令的输出
+
+	LimitBytesPerSec        int64 // 所有 worker 加起来的聚合限速，字节/秒，0 表示不限速
+	PerConnLimitBytesPerSec int64 // 单个连接的限速，防止某个慢速镜像独占带宽
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval 是进度采样/渲染的周期
+const progressInterval = 200 * time.Millisecond
+
+// ewmaAlpha 控制速度平滑程度，越大越贴近瞬时速度，越小越平滑
+const ewmaAlpha = 0.3
+
+// ChunkProgress 是某一个分片当前的下载进度
+type ChunkProgress struct {
+	Index      int
+	Start      int64
+	End        int64
+	Downloaded int64
+}
+
+// Progress 是某一时刻的整体下载进度快照，库的使用者可以订阅它自己渲染 TUI
+// 或者对外提供一个 HTTP 状态接口，不必依赖 CLI 自带的渲染逻辑
+type Progress struct {
+	Downloaded int64
+	Total      int64
+	Speed      float64 // 字节/秒，EWMA 平滑后的速度
+	ETA        time.Duration
+	PerChunk   []ChunkProgress
+}
+
+// countingReader 包一层 io.Reader，每次 Read 都把读到的字节数原子地加到 counter 上，
+// worker 的读取循环套用它就能让进度统计做到字节级精确，而不是按分片数量打点
+type countingReader struct {
+	r       io.Reader
+	counter *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		atomic.AddInt64(c.counter, int64(n))
+	}
+	return n, err
+}
+
+// ProgressReporter 由暴露实时进度的 Fetcher 实现；CLI 渲染器通过类型断言拿到它，
+// 库的其它使用者也可以拿着同一个 Progress 通道驱动自己的界面
+type ProgressReporter interface {
+	Progress() <-chan Progress
+}
+
+// Progress 返回只读的进度通道，每 progressInterval 推送一次最新快照
+func (h *HTTPFetcher) Progress() <-chan Progress {
+	return h.progressCh
+}
+
+// ewmaSpeed 用 ewmaAlpha 把瞬时速度融合进之前的平滑速度
+func ewmaSpeed(prevSmoothed, instant float64) float64 {
+	return ewmaAlpha*instant + (1-ewmaAlpha)*prevSmoothed
+}
+
+// etaFor 按当前平滑速度估算剩余时间；速度未知（<=0）或总大小未知时返回 0
+func etaFor(total, downloaded int64, smoothedSpeed float64) time.Duration {
+	if smoothedSpeed <= 0 {
+		return 0
+	}
+	secondsLeft := float64(total-downloaded) / smoothedSpeed
+	return time.Duration(secondsLeft * float64(time.Second))
+}
+
+// reportProgress 周期性地从 scheduler 读取聚合字节数和各分片状态，计算平滑速度
+// 和 ETA，推送到 progressCh；通道满了就丢弃旧快照，只保留最新的
+func (h *HTTPFetcher) reportProgress(sched *scheduler, done <-chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var lastBytes int64
+	var lastAt = time.Now()
+	var smoothedSpeed float64
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			downloaded := sched.totalBytesDone()
+			elapsed := now.Sub(lastAt).Seconds()
+			if elapsed > 0 {
+				instant := float64(downloaded-lastBytes) / elapsed
+				smoothedSpeed = ewmaSpeed(smoothedSpeed, instant)
+			}
+			lastBytes, lastAt = downloaded, now
+
+			p := Progress{
+				Downloaded: downloaded,
+				Total:      h.res.ContentLength,
+				Speed:      smoothedSpeed,
+				PerChunk:   sched.snapshotChunks(),
+			}
+			p.ETA = etaFor(p.Total, p.Downloaded, smoothedSpeed)
+
+			select {
+			case <-h.progressCh:
+			default:
+			}
+			h.progressCh <- p
+		}
+	}
+}
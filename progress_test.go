@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCountingReaderAddsBytesRead(t *testing.T) {
+	var counter int64
+	r := &countingReader{r: bytes.NewReader([]byte("hello world")), counter: &counter}
+
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("expected to read 5 bytes, got %d", n)
+	}
+	if counter != 5 {
+		t.Fatalf("expected counter to be 5 after first read, got %d", counter)
+	}
+
+	io.ReadAll(r)
+	if counter != 11 {
+		t.Fatalf("expected counter to reach 11 after reading the rest, got %d", counter)
+	}
+}
+
+func TestCountingReaderDoesNotCountOnError(t *testing.T) {
+	var counter int64
+	r := &countingReader{r: &errorReader{err: errors.New("boom")}, counter: &counter}
+
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	if n != 0 || err == nil {
+		t.Fatalf("expected a read error with 0 bytes, got n=%d err=%v", n, err)
+	}
+	if counter != 0 {
+		t.Fatalf("expected counter to stay 0 on error, got %d", counter)
+	}
+}
+
+type errorReader struct{ err error }
+
+func (e *errorReader) Read([]byte) (int, error) { return 0, e.err }
+
+func TestEwmaSpeedBlendsTowardInstant(t *testing.T) {
+	got := ewmaSpeed(100, 200)
+	want := ewmaAlpha*200 + (1-ewmaAlpha)*100
+	if got != want {
+		t.Fatalf("ewmaSpeed(100, 200) = %v, want %v", got, want)
+	}
+
+	if got := ewmaSpeed(0, 50); got != ewmaAlpha*50 {
+		t.Fatalf("ewmaSpeed(0, 50) = %v, want %v", got, ewmaAlpha*50)
+	}
+}
+
+func TestEtaFor(t *testing.T) {
+	if eta := etaFor(1000, 0, 0); eta != 0 {
+		t.Fatalf("expected zero ETA when speed is unknown, got %v", eta)
+	}
+	if eta := etaFor(1000, 0, -5); eta != 0 {
+		t.Fatalf("expected zero ETA for a non-positive speed, got %v", eta)
+	}
+
+	eta := etaFor(1000, 500, 100)
+	if eta.Seconds() != 5 {
+		t.Fatalf("expected 5s remaining at 100 bytes/s for 500 bytes left, got %v", eta)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+// Request 描述一次资源解析请求
+type Request struct {
+	URL string
+
+	// Options 可选；提前传入时，探测请求会先用它装配好 client（鉴权、TLS、
+	// 自定义 Header 等），避免探测请求在需要鉴权或自定义 TLS 配置的站点上失败
+	Options *Options
+}
+
+// Resource 是 Resolve 阶段得到的、与具体协议无关的资源描述信息
+type Resource struct {
+	URL           string // 原始请求的 URL
+	FinalURL      string // 跟随重定向后的最终 URL
+	Filename      string
+	ContentLength int64
+	AcceptRanges  bool
+	ETag          string
+	LastModified  string
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// minChunkSize 动态切分的最小分片大小，每个分片由一个 worker 独立请求
+	minChunkSize int64 = 1 << 20 // 1 MiB
+	// maxChunkSize 动态切分的最大分片大小
+	maxChunkSize int64 = 4 << 20 // 4 MiB
+
+	// minStealSize 被偷取分片的剩余部分至少要有这么大才值得拆分，
+	// 否则拆分带来的一次额外请求开销得不偿失
+	minStealSize int64 = 256 << 10 // 256 KiB
+
+	// throughput 采样与 worker 数调整的周期
+	tuneInterval = 2 * time.Second
+)
+
+// task 是调度队列中的一个下载任务，对应续传记录里的一个 ChunkState
+type task struct {
+	journalIndex int
+	start        int64
+	end          int64 // 闭区间右端，可能在运行中被其他 worker 缩短（偷取尾部）
+	doneAtStart  int64 // 领取任务时续传记录里该分片已经完成的字节数
+}
+
+// inFlightTask 正在被某个 worker 处理的任务，cursor 记录已经写盘到哪个绝对偏移，
+// 偷取者据此计算还剩多少没人认领的尾部数据
+type inFlightTask struct {
+	mu     sync.Mutex
+	t      *task
+	cursor int64
+}
+
+// WorkerStats 记录单个 worker 的吞吐量，供调度器做 AIMD 决策和 CLI 展示
+type WorkerStats struct {
+	ID         int
+	bytesTotal int64 // 原子计数，本次采样周期内新增字节数通过 delta 计算
+}
+
+// BytesDone 返回该 worker 目前累计下载的字节数
+func (w *WorkerStats) BytesDone() int64 {
+	return atomic.LoadInt64(&w.bytesTotal)
+}
+
+// scheduler 是动态分片下载的工作窃取调度器：把文件切成很多定长小块放进队列，
+// numRoutines 个 worker 并发消费；队列耗尽后，空闲 worker 会去"偷"还在下载的
+// 分片的剩余尾部，避免单个慢速连接拖慢整体完成时间
+type scheduler struct {
+	h *HTTPFetcher
+
+	mu       sync.Mutex
+	pending  []*task
+	inFlight []*inFlightTask
+
+	activeWorkers int32 // 当前存活的 worker 数，可在运行中增减
+	nextWorkerID  int32
+	shrinkTarget  int32 // >0 时表示还需要多少个 worker 主动退出
+	lastDelta     int64 // 上一采样周期新增的字节数，供 AIMD 判断是否进入平台期
+
+	statsMu sync.Mutex
+	stats   []*WorkerStats
+
+	wg    sync.WaitGroup
+	errCh chan error
+}
+
+// newScheduler 用续传记录里尚未完成的分片初始化调度队列
+func newScheduler(h *HTTPFetcher) *scheduler {
+	s := &scheduler{h: h, errCh: make(chan error, len(h.journal.Chunks)+8)}
+	for i, c := range h.journal.Chunks {
+		resumeOffset := c.Start + c.Done
+		if resumeOffset > c.End {
+			continue // 该分片已在之前的运行中下载完成
+		}
+		s.pending = append(s.pending, &task{journalIndex: i, start: resumeOffset, end: c.End, doneAtStart: c.Done})
+	}
+	return s
+}
+
+// run 启动 numRoutines 个 worker 并阻塞直到所有分片下载完成或出错
+func (s *scheduler) run(numRoutines int) error {
+	s.spawnWorkers(numRoutines)
+	go s.tuneWorkerCount()
+
+	s.wg.Wait()
+	close(s.errCh)
+
+	for err := range s.errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spawnWorkers 新增 n 个 worker 协程，用于初始启动和运行时扩容
+func (s *scheduler) spawnWorkers(n int) {
+	for i := 0; i < n; i++ {
+		id := int(atomic.AddInt32(&s.nextWorkerID, 1))
+		stats := &WorkerStats{ID: id}
+		s.statsMu.Lock()
+		s.stats = append(s.stats, stats)
+		s.statsMu.Unlock()
+
+		atomic.AddInt32(&s.activeWorkers, 1)
+		s.wg.Add(1)
+		go s.worker(stats)
+	}
+}
+
+// worker 是调度器的工作循环：领取任务 -> 下载 -> 领取下一个任务，
+// 队列和可偷取的尾部都耗尽后退出
+func (s *scheduler) worker(stats *WorkerStats) {
+	defer func() {
+		atomic.AddInt32(&s.activeWorkers, -1)
+		s.wg.Done()
+	}()
+
+	client := s.h.newKeepAliveClient()
+
+	for {
+		if s.tryClaimShrink() {
+			// 调度器希望缩减 worker 数，当前 worker 主动退出
+			return
+		}
+
+		t := s.nextTask()
+		if t == nil {
+			return
+		}
+
+		// 每领取一个新分片都重新读取限速值构造限速器，这样 SetPerConnLimit
+		// 对长期存活的 worker 也能在下一个分片生效，而不用等它被 AIMD 换掉
+		perConnLimiter := newRateLimiter(s.h.currentPerConnLimit())
+
+		inFlight := &inFlightTask{t: t, cursor: t.start}
+		s.addInFlight(inFlight)
+		err := s.h.downloadTask(client, inFlight, stats, perConnLimiter)
+		s.removeInFlight(inFlight)
+
+		if err != nil {
+			s.errCh <- err
+			return
+		}
+	}
+}
+
+// nextTask 从队列头部取一个任务；队列为空时尝试偷取其他 worker 正在下载的尾部
+func (s *scheduler) nextTask() *task {
+	s.mu.Lock()
+	if len(s.pending) > 0 {
+		t := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		return t
+	}
+	s.mu.Unlock()
+
+	return s.steal()
+}
+
+// steal 在所有 in-flight 分片里找剩余字节最多的一个，把它的 Range 尾部切给自己，
+// 这样一个慢速分片不会拖着整体进度迟迟完成不了（长尾问题）
+func (s *scheduler) steal() *task {
+	s.mu.Lock()
+	candidates := append([]*inFlightTask(nil), s.inFlight...)
+	s.mu.Unlock()
+
+	var victim *inFlightTask
+	var victimRemaining int64
+	for _, c := range candidates {
+		c.mu.Lock()
+		remaining := c.t.end - c.cursor
+		c.mu.Unlock()
+		if remaining > victimRemaining {
+			victim = c
+			victimRemaining = remaining
+		}
+	}
+
+	if victim == nil || victimRemaining < minStealSize {
+		return nil
+	}
+
+	victim.mu.Lock()
+	defer victim.mu.Unlock()
+
+	remaining := victim.t.end - victim.cursor
+	if remaining < minStealSize {
+		return nil
+	}
+
+	mid := victim.cursor + remaining/2
+	stolen := &task{journalIndex: s.h.splitJournalChunk(victim.t.journalIndex, mid), start: mid + 1, end: victim.t.end}
+	victim.t.end = mid
+
+	return stolen
+}
+
+func (s *scheduler) addInFlight(t *inFlightTask) {
+	s.mu.Lock()
+	s.inFlight = append(s.inFlight, t)
+	s.mu.Unlock()
+}
+
+func (s *scheduler) removeInFlight(target *inFlightTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.inFlight {
+		if t == target {
+			s.inFlight = append(s.inFlight[:i], s.inFlight[i+1:]...)
+			return
+		}
+	}
+}
+
+// tryClaimShrink 在 shrinkTarget 为正时原子地认领一次退出名额，
+// 让 tuneWorkerCount 算出的"应该少几个 worker"精确生效，而不会一次性退出过多
+func (s *scheduler) tryClaimShrink() bool {
+	for {
+		target := atomic.LoadInt32(&s.shrinkTarget)
+		if target <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&s.shrinkTarget, target, target-1) {
+			return true
+		}
+	}
+}
+
+// tuneWorkerCount 周期性采样聚合吞吐量，按 AIMD 策略增减 worker 数：
+// 吞吐量仍在上升就加性增加一个 worker，吞吐量出现平台期就乘性减少
+func (s *scheduler) tuneWorkerCount() {
+	ticker := time.NewTicker(tuneInterval)
+	defer ticker.Stop()
+
+	var lastTotal int64
+	plateauRounds := 0
+
+	for range ticker.C {
+		if atomic.LoadInt32(&s.activeWorkers) == 0 {
+			return
+		}
+
+		total := s.totalBytesDone()
+		delta := total - lastTotal
+		lastTotal = total
+
+		if delta <= 0 {
+			continue
+		}
+
+		if float64(delta) < float64(s.lastDelta)*1.05 {
+			plateauRounds++
+		} else {
+			plateauRounds = 0
+		}
+		s.lastDelta = delta
+
+		if plateauRounds >= 2 {
+			// 吞吐量已经不再随 worker 数增长而提升，乘性减少
+			current := int(atomic.LoadInt32(&s.activeWorkers))
+			atomic.StoreInt32(&s.shrinkTarget, int32(current/2))
+			plateauRounds = 0
+			continue
+		}
+
+		// 吞吐量还在增长，加性增加一个 worker 探探路
+		if s.hasRemainingWork() {
+			s.spawnWorkers(1)
+		}
+	}
+}
+
+// hasRemainingWork 报告是否还有待领取或正在下载的分片
+func (s *scheduler) hasRemainingWork() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending) > 0 || len(s.inFlight) > 0
+}
+
+// snapshotChunks 返回当前正在下载的各分片的进度快照，供 Progress 展示使用
+func (s *scheduler) snapshotChunks() []ChunkProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chunks := make([]ChunkProgress, 0, len(s.inFlight))
+	for _, in := range s.inFlight {
+		in.mu.Lock()
+		chunks = append(chunks, ChunkProgress{
+			Index:      in.t.journalIndex,
+			Start:      in.t.start,
+			End:        in.t.end,
+			Downloaded: in.cursor - in.t.start,
+		})
+		in.mu.Unlock()
+	}
+	return chunks
+}
+
+func (s *scheduler) totalBytesDone() int64 {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	var total int64
+	for _, st := range s.stats {
+		total += st.BytesDone()
+	}
+	return total
+}
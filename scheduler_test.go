@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestScheduler(t *testing.T, chunks []ChunkState) *scheduler {
+	t.Helper()
+	j := &Journal{path: filepath.Join(t.TempDir(), "test.hdpart"), Chunks: chunks}
+	h := &HTTPFetcher{journal: j}
+	return &scheduler{h: h, errCh: make(chan error, 1)}
+}
+
+func TestSchedulerStealPicksLargestRemainingAndSplitsInHalf(t *testing.T) {
+	s := newTestScheduler(t, []ChunkState{{Start: 0, End: 999999}})
+
+	small := &inFlightTask{t: &task{journalIndex: 0, start: 0, end: 999999}, cursor: 999000}
+	big := &inFlightTask{t: &task{journalIndex: 0, start: 0, end: 999999}, cursor: 0}
+	s.inFlight = []*inFlightTask{small, big}
+
+	stolen := s.steal()
+	if stolen == nil {
+		t.Fatal("expected a stolen task from the largest remaining in-flight chunk")
+	}
+
+	wantMid := int64(0 + (999999-0)/2)
+	if big.t.end != wantMid {
+		t.Fatalf("victim's end should shrink to the midpoint %d, got %d", wantMid, big.t.end)
+	}
+	if stolen.start != wantMid+1 || stolen.end != 999999 {
+		t.Fatalf("stolen task should cover (%d, 999999], got (%d, %d]", wantMid, stolen.start, stolen.end)
+	}
+}
+
+func TestSchedulerStealRefusesBelowMinStealSize(t *testing.T) {
+	s := newTestScheduler(t, []ChunkState{{Start: 0, End: 1000}})
+
+	tiny := &inFlightTask{t: &task{journalIndex: 0, start: 0, end: 1000}, cursor: 900}
+	s.inFlight = []*inFlightTask{tiny}
+
+	if stolen := s.steal(); stolen != nil {
+		t.Fatalf("expected no steal when remaining bytes are below minStealSize, got %+v", stolen)
+	}
+}
+
+func TestSchedulerStealReturnsNilWithNoInFlightTasks(t *testing.T) {
+	s := newTestScheduler(t, nil)
+	if stolen := s.steal(); stolen != nil {
+		t.Fatalf("expected nil when there is nothing in flight, got %+v", stolen)
+	}
+}
+
+func TestSchedulerTryClaimShrink(t *testing.T) {
+	s := newTestScheduler(t, nil)
+	s.shrinkTarget = 2
+
+	if !s.tryClaimShrink() {
+		t.Fatal("expected to claim a shrink slot")
+	}
+	if !s.tryClaimShrink() {
+		t.Fatal("expected to claim the second shrink slot")
+	}
+	if s.tryClaimShrink() {
+		t.Fatal("expected no more shrink slots to claim")
+	}
+}
+
+func TestSchedulerHasRemainingWork(t *testing.T) {
+	s := newTestScheduler(t, nil)
+	if s.hasRemainingWork() {
+		t.Fatal("expected no remaining work on an empty scheduler")
+	}
+
+	s.pending = append(s.pending, &task{})
+	if !s.hasRemainingWork() {
+		t.Fatal("expected remaining work once a task is pending")
+	}
+}